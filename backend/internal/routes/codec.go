@@ -0,0 +1,81 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"os"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func init() {
+	gob.Register(Payment{})
+}
+
+// Magic byte prefixes let a consumer detect which codec wrote an entry, so a
+// rolling deploy that changes PAYMENT_CODEC doesn't silently misdecode
+// payments queued by the previous version.
+const (
+	codecMagicGob     byte = 0x01
+	codecMagicMsgPack byte = 0x02
+)
+
+var ErrCodecMismatch = errors.New("routes: payment was encoded with a different codec")
+
+// Codec marshals Payment values onto payments:stream. encoding/json dominates
+// allocations on the POST /payments hot path at load, so this lets that be
+// swapped for a cheaper binary format without touching the handler or the
+// worker loop.
+type Codec interface {
+	Encode(Payment) ([]byte, error)
+	Decode([]byte, *Payment) error
+}
+
+// CodecFromEnv selects a Codec via PAYMENT_CODEC ("gob" or "msgpack"),
+// defaulting to msgpack.
+func CodecFromEnv() Codec {
+	switch os.Getenv("PAYMENT_CODEC") {
+	case "gob":
+		return GobCodec{}
+	default:
+		return MsgPackCodec{}
+	}
+}
+
+// GobCodec encodes payments with encoding/gob.
+type GobCodec struct{}
+
+func (GobCodec) Encode(p Payment) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(codecMagicGob)
+	if err := gob.NewEncoder(&buf).Encode(p); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) Decode(data []byte, p *Payment) error {
+	if len(data) == 0 || data[0] != codecMagicGob {
+		return ErrCodecMismatch
+	}
+	return gob.NewDecoder(bytes.NewReader(data[1:])).Decode(p)
+}
+
+// MsgPackCodec encodes payments with msgpack, the default codec.
+type MsgPackCodec struct{}
+
+func (MsgPackCodec) Encode(p Payment) ([]byte, error) {
+	body, err := msgpack.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{codecMagicMsgPack}, body...), nil
+}
+
+func (MsgPackCodec) Decode(data []byte, p *Payment) error {
+	if len(data) == 0 || data[0] != codecMagicMsgPack {
+		return ErrCodecMismatch
+	}
+	return msgpack.Unmarshal(data[1:], p)
+}
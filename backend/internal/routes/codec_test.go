@@ -0,0 +1,150 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+func testPayment() Payment {
+	return Payment{
+		CorrelationId: uuid.New(),
+		Amount:        19.9,
+		ReceivedAt:    time.Now().UTC(),
+	}
+}
+
+func testRoundTrip(t *testing.T, c Codec) {
+	t.Helper()
+	want := testPayment()
+
+	data, err := c.Encode(want)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	var got Payment
+	if err := c.Decode(data, &got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got.CorrelationId != want.CorrelationId || got.Amount != want.Amount || !got.ReceivedAt.Equal(want.ReceivedAt) {
+		t.Fatalf("round trip mismatch: got %+v, want %+v", got, want)
+	}
+}
+
+func TestGobCodecRoundTrip(t *testing.T) {
+	testRoundTrip(t, GobCodec{})
+}
+
+func TestMsgPackCodecRoundTrip(t *testing.T) {
+	testRoundTrip(t, MsgPackCodec{})
+}
+
+func TestCodecDecodeRejectsMismatchedMagic(t *testing.T) {
+	p := testPayment()
+
+	gobData, err := GobCodec{}.Encode(p)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var out Payment
+	if err := (MsgPackCodec{}).Decode(gobData, &out); err != ErrCodecMismatch {
+		t.Fatalf("Decode: got %v, want ErrCodecMismatch", err)
+	}
+
+	msgpackData, err := MsgPackCodec{}.Encode(p)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if err := (GobCodec{}).Decode(msgpackData, &out); err != ErrCodecMismatch {
+		t.Fatalf("Decode: got %v, want ErrCodecMismatch", err)
+	}
+}
+
+func benchmarkCodecRoundTrip(b *testing.B, c Codec) {
+	p := testPayment()
+	var out Payment
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		data, err := c.Encode(p)
+		if err != nil {
+			b.Fatalf("Encode: %v", err)
+		}
+		if err := c.Decode(data, &out); err != nil {
+			b.Fatalf("Decode: %v", err)
+		}
+	}
+}
+
+// BenchmarkGobCodec and BenchmarkMsgPackCodec compare encode/decode cost
+// directly, since that's the claim CodecFromEnv's default rests on.
+func BenchmarkGobCodec(b *testing.B) {
+	benchmarkCodecRoundTrip(b, GobCodec{})
+}
+
+func BenchmarkMsgPackCodec(b *testing.B) {
+	benchmarkCodecRoundTrip(b, MsgPackCodec{})
+}
+
+// benchmarkPostPayments drives POST /payments end to end against a real
+// (miniredis-backed) server, so the codec comparison reflects the actual hot
+// path CodecFromEnv's choice affects, not just raw Encode/Decode cost.
+func benchmarkPostPayments(b *testing.B, codec Codec) {
+	b.Helper()
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		b.Fatalf("miniredis.Run: %v", err)
+	}
+	defer mr.Close()
+
+	queue := NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	processor := NewPaymentProcessor(0, queue, queue, codec)
+	handler := &Handler{paymentProcessor: processor}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /payments", handler.HandlePayments)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		body, err := json.Marshal(map[string]any{
+			"correlationId": uuid.New().String(),
+			"amount":        19.9,
+		})
+		if err != nil {
+			b.Fatalf("Marshal: %v", err)
+		}
+
+		resp, err := http.Post(server.URL+"/payments", "application/json", bytes.NewReader(body))
+		if err != nil {
+			b.Fatalf("POST /payments: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusAccepted {
+			b.Fatalf("POST /payments: got status %d", resp.StatusCode)
+		}
+	}
+}
+
+// BenchmarkPostPaymentsGobCodec and BenchmarkPostPaymentsMsgPackCodec compare
+// end-to-end POST /payments throughput under each codec, complementing the
+// Encode/Decode-only benchmarks above with the RPS CodecFromEnv's choice is
+// actually meant to move.
+func BenchmarkPostPaymentsGobCodec(b *testing.B) {
+	benchmarkPostPayments(b, GobCodec{})
+}
+
+func BenchmarkPostPaymentsMsgPackCodec(b *testing.B) {
+	benchmarkPostPayments(b, MsgPackCodec{})
+}
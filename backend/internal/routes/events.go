@@ -0,0 +1,99 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const heartbeatInterval = 15 * time.Second
+
+const eventsChannel = "payments:events"
+
+// PaymentEvent is published to Redis Pub/Sub on every lifecycle transition a
+// payment goes through, and forwarded verbatim to SSE subscribers.
+type PaymentEvent struct {
+	CorrelationId uuid.UUID `json:"correlationId"`
+	Event         string    `json:"event"`
+	Processor     string    `json:"processor,omitempty"`
+	At            time.Time `json:"at"`
+}
+
+func eventsChannelFor(correlationId uuid.UUID) string {
+	return fmt.Sprintf("%s:%s", eventsChannel, correlationId.String())
+}
+
+// PublishEvent broadcasts a lifecycle transition on both the global channel
+// (for operators watching the whole queue drain) and the per-correlationId
+// channel (for a client tracking a single payment).
+func (s *RedisStore) PublishEvent(ctx context.Context, event PaymentEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if err := s.redisClient.Publish(ctx, eventsChannel, payload).Err(); err != nil {
+		return err
+	}
+	return s.redisClient.Publish(ctx, eventsChannelFor(event.CorrelationId), payload).Err()
+}
+
+// HandlePaymentEvents streams payment lifecycle transitions as
+// Server-Sent Events. Without a correlationId path value it streams every
+// payment's transitions; with one it streams only that payment's.
+func (h *Handler) HandlePaymentEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	channel := eventsChannel
+	if raw := r.PathValue("correlationId"); raw != "" {
+		correlationId, err := uuid.Parse(raw)
+		if err != nil {
+			http.Error(w, "Invalid correlationId", http.StatusBadRequest)
+			return
+		}
+		channel = eventsChannelFor(correlationId)
+	}
+
+	queue := h.paymentProcessor.queue
+	sub := queue.redisClient.Subscribe(r.Context(), channel)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case msg, open := <-ch:
+			if !open {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", msg.Payload); err != nil {
+				log.Printf("Error writing SSE event: %v", err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
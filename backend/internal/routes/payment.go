@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Payment is the shape accepted by POST /payments and the shape persisted
+// onto the processing queue.
+type Payment struct {
+	CorrelationId uuid.UUID `json:"correlationId"`
+	Amount        float64   `json:"amount"`
+	ReceivedAt    time.Time `json:"requestedAt,omitempty"`
+}
+
+// ProcessorSummary aggregates how many payments a single processor (default
+// or fallback) has settled and their combined amount.
+type ProcessorSummary struct {
+	TotalRequests int64   `json:"totalRequests"`
+	TotalAmount   float64 `json:"totalAmount"`
+}
+
+// PaymentSummary is the response body for GET /payments-summary.
+type PaymentSummary struct {
+	Default  ProcessorSummary `json:"default"`
+	Fallback ProcessorSummary `json:"fallback"`
+	// Pending, DLQSize and Lag describe the live state of payments:stream,
+	// so NewPaymentProcessor's worker count can be tuned against real
+	// backlog instead of guesswork.
+	Pending int64 `json:"pending"`
+	DLQSize int64 `json:"dlq_size"`
+	Lag     int64 `json:"lag"`
+}
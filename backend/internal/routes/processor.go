@@ -0,0 +1,276 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// transition persists status as the payment's idempotency-facing state (the
+// vocabulary HandlePayments echoes back to retrying clients) and publishes
+// event as the finer-grained SSE notification for /payments/events.
+func (p *PaymentProcessor) transition(ctx context.Context, payment Payment, status, event, processor string) {
+	if err := p.queue.SetStatus(ctx, payment.CorrelationId, status, processor); err != nil {
+		log.Printf("Error recording %s status: %v", status, err)
+	}
+	if err := p.queue.PublishEvent(ctx, PaymentEvent{
+		CorrelationId: payment.CorrelationId,
+		Event:         event,
+		Processor:     processor,
+		At:            time.Now().UTC(),
+	}); err != nil {
+		log.Printf("Error publishing %s event: %v", event, err)
+	}
+}
+
+// PaymentProcessor drains payments:stream through a "processors" consumer
+// group, attempting the default processor before falling back to the
+// fallback one. An entry is only XACK'd once a processor accepts it; a
+// crashed worker or a payment that fails on both processors both leave it
+// pending. A background reclaim loop pages through payments:stream's
+// pending list (XPENDING) in batches of reclaimBatchSize, handing such
+// entries back to a live consumer for redelivery (XCLAIM), and
+// dead-lettering ones that have exceeded MaxDeliveries.
+//
+// queue handles stream consumption, idempotency, and lifecycle pub/sub,
+// which are always Redis-native. store is the pluggable ledger selected via
+// STORE_BACKEND, used only to record and summarize settled payments.
+type PaymentProcessor struct {
+	queue             *RedisStore
+	store             Store
+	workers           int
+	codec             Codec
+	httpClient        *http.Client
+	defaultURL        string
+	fallbackURL       string
+	visibilityTimeout time.Duration
+	maxDeliveries     int64
+}
+
+func NewPaymentProcessor(workers int, queue *RedisStore, store Store, codec Codec) *PaymentProcessor {
+	p := &PaymentProcessor{
+		queue:             queue,
+		store:             store,
+		workers:           workers,
+		codec:             codec,
+		httpClient:        &http.Client{Timeout: 5 * time.Second},
+		defaultURL:        envOr("PAYMENT_PROCESSOR_URL_DEFAULT", "http://payment-processor-default:8080"),
+		fallbackURL:       envOr("PAYMENT_PROCESSOR_URL_FALLBACK", "http://payment-processor-fallback:8080"),
+		visibilityTimeout: envDurationOr("PAYMENT_VISIBILITY_TIMEOUT", 30*time.Second),
+		maxDeliveries:     envInt64Or("PAYMENT_MAX_DELIVERIES", 5),
+	}
+
+	if err := queue.EnsureConsumerGroup(context.Background()); err != nil {
+		log.Printf("Error creating consumer group: %v", err)
+	}
+	for i := 0; i < workers; i++ {
+		go p.run(context.Background(), fmt.Sprintf("worker-%d", i))
+	}
+	go p.reclaimLoop(context.Background())
+	return p
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func envDurationOr(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return fallback
+}
+
+func envInt64Or(key string, fallback int64) int64 {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+func (p *PaymentProcessor) run(ctx context.Context, consumer string) {
+	for {
+		streams, err := p.queue.ReadGroup(ctx, consumer, 1)
+		if err != nil {
+			log.Printf("Error reading from %s: %v", streamKey, err)
+			continue
+		}
+
+		for _, stream := range streams {
+			for _, message := range stream.Messages {
+				p.handle(ctx, message)
+			}
+		}
+	}
+}
+
+func (p *PaymentProcessor) handle(ctx context.Context, message redis.XMessage) {
+	body, ok := message.Values[streamField].(string)
+	if !ok {
+		log.Printf("Entry %s missing %s field, dead-lettering", message.ID, streamField)
+		if err := p.queue.DeadLetter(ctx, message.ID, nil); err != nil {
+			log.Printf("Error dead-lettering %s: %v", message.ID, err)
+		}
+		return
+	}
+
+	var payment Payment
+	if err := p.codec.Decode([]byte(body), &payment); err != nil {
+		log.Printf("Error decoding entry %s: %v", message.ID, err)
+		return
+	}
+
+	if !p.process(ctx, payment) {
+		// Leave the entry pending: reclaimStale will redeliver it once
+		// visibilityTimeout elapses, and dead-letter it after MaxDeliveries.
+		return
+	}
+
+	if err := p.queue.Ack(ctx, message.ID); err != nil {
+		log.Printf("Error acking %s: %v", message.ID, err)
+	}
+}
+
+// reclaimBatchSize bounds each PendingSince call; reclaimStale pages past it
+// with the exclusive-range cursor so a tick still covers the whole pending
+// list rather than stalling on whatever sorts first.
+const reclaimBatchSize = 100
+
+// reclaimLoop periodically hands entries idle longer than VisibilityTimeout
+// back to a live consumer, or dead-letters them once they have been
+// delivered MaxDeliveries times.
+func (p *PaymentProcessor) reclaimLoop(ctx context.Context) {
+	ticker := time.NewTicker(p.visibilityTimeout)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		p.reclaimStale(ctx)
+	}
+}
+
+// reclaimStale walks the full pending list in reclaimBatchSize pages,
+// cursoring past the last entry of each page, so a backlog bigger than one
+// batch still makes complete progress within a single tick.
+func (p *PaymentProcessor) reclaimStale(ctx context.Context) {
+	start := "-"
+	for {
+		stale, err := p.queue.PendingSince(ctx, p.visibilityTimeout, start, reclaimBatchSize)
+		if err != nil {
+			log.Printf("Error listing stale entries: %v", err)
+			return
+		}
+
+		for _, entry := range stale {
+			if entry.RetryCount >= p.maxDeliveries {
+				p.deadLetterStale(ctx, entry.ID)
+				continue
+			}
+
+			messages, err := p.queue.Claim(ctx, "reclaimer", p.visibilityTimeout, entry.ID)
+			if err != nil {
+				log.Printf("Error claiming %s: %v", entry.ID, err)
+				continue
+			}
+			for _, message := range messages {
+				p.handle(ctx, message)
+			}
+		}
+
+		if int64(len(stale)) < reclaimBatchSize {
+			return
+		}
+		start = "(" + stale[len(stale)-1].ID
+	}
+}
+
+// deadLetterStale moves entry id onto payments:dlq once it has exceeded
+// MaxDeliveries. This is the only place that writes a genuinely terminal
+// "failed" status/event: process's own both-processors-rejected path writes
+// "retrying" instead, since reclaimStale may still redeliver and settle it.
+func (p *PaymentProcessor) deadLetterStale(ctx context.Context, id string) {
+	messages, err := p.queue.Claim(ctx, "reclaimer", 0, id)
+	if err != nil || len(messages) == 0 {
+		log.Printf("Error claiming %s for dead-letter: %v", id, err)
+		return
+	}
+	body, _ := messages[0].Values[streamField].(string)
+	if err := p.queue.DeadLetter(ctx, id, []byte(body)); err != nil {
+		log.Printf("Error dead-lettering %s: %v", id, err)
+		return
+	}
+
+	var payment Payment
+	if err := p.codec.Decode([]byte(body), &payment); err != nil {
+		log.Printf("Error decoding dead-lettered entry %s for status update: %v", id, err)
+		return
+	}
+	p.transition(ctx, payment, "failed", "failed", "")
+}
+
+// process attempts the default processor, then the fallback, reporting
+// whether the payment settled. handle only acks the stream entry when this
+// returns true, so a payment that fails on both processors stays pending for
+// reclaimStale to retry rather than being dropped on the floor.
+func (p *PaymentProcessor) process(ctx context.Context, payment Payment) bool {
+	p.transition(ctx, payment, "dispatched", "dispatched", "")
+
+	if p.send(ctx, p.defaultURL, payment) {
+		p.settle(ctx, payment, "default")
+		return true
+	}
+
+	p.transition(ctx, payment, "dispatched", "retrying", "")
+	if p.send(ctx, p.fallbackURL, payment) {
+		p.settle(ctx, payment, "fallback")
+		return true
+	}
+
+	log.Printf("Payment %s failed on both processors, leaving it pending for a retry", payment.CorrelationId)
+	p.transition(ctx, payment, "retrying", "retrying", "")
+	return false
+}
+
+func (p *PaymentProcessor) settle(ctx context.Context, payment Payment, processor string) {
+	if err := p.store.RecordProcessed(ctx, processor, payment); err != nil {
+		log.Printf("Error recording %s payment: %v", processor, err)
+	}
+	p.transition(ctx, payment, "processed-"+processor, "succeeded-"+processor, processor)
+}
+
+func (p *PaymentProcessor) send(ctx context.Context, url string, payment Payment) bool {
+	body, err := json.Marshal(payment)
+	if err != nil {
+		log.Printf("Error marshalling payment for %s: %v", url, err)
+		return false
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url+"/payments", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Error building request for %s: %v", url, err)
+		return false
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode == http.StatusOK
+}
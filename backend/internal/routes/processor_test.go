@@ -0,0 +1,170 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeStore is a no-op Store so process's ack-on-success contract can be
+// exercised without a Postgres/hybrid backend.
+type fakeStore struct{}
+
+func (fakeStore) RecordProcessed(ctx context.Context, processor string, payment Payment) error {
+	return nil
+}
+func (fakeStore) GetSummary(ctx context.Context) (*PaymentSummary, error) { return nil, nil }
+func (fakeStore) GetSummaryWithTime(ctx context.Context, from, to string) (*PaymentSummary, error) {
+	return nil, nil
+}
+func (fakeStore) PurgeAllData(ctx context.Context) error { return nil }
+
+// newTestProcessor points queue at an address nothing is listening on:
+// transition's SetStatus/PublishEvent calls fail fast and are merely logged,
+// which is fine here since process's return value depends only on what the
+// processor endpoints answer, not on queue bookkeeping succeeding.
+func newTestProcessor(t *testing.T) *PaymentProcessor {
+	t.Helper()
+	queue := NewRedisStore(redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"}))
+	return NewPaymentProcessor(0, queue, fakeStore{}, MsgPackCodec{})
+}
+
+// probeExclusiveRangeSupport checks whether queue's backing Redis honors
+// XPENDING's exclusive-range start IDs, by round-tripping one throwaway
+// entry through a real consumer group.
+func probeExclusiveRangeSupport(ctx context.Context, queue *RedisStore) error {
+	codec := MsgPackCodec{}
+	payload, err := codec.Encode(testPayment())
+	if err != nil {
+		return err
+	}
+	id, err := queue.Enqueue(ctx, payload)
+	if err != nil {
+		return err
+	}
+	defer queue.Ack(ctx, id)
+
+	if _, err := queue.ReadGroup(ctx, "probe-consumer", 1); err != nil {
+		return err
+	}
+
+	_, err = queue.PendingSince(ctx, 0, "("+id, 1)
+	return err
+}
+
+func testServer(status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+}
+
+// TestProcessAcksOnlyOnSettlement locks in handle's ack-on-success contract:
+// process must report failure when both the default and fallback processors
+// reject a payment, so handle leaves the stream entry pending for
+// reclaimStale to retry and eventually dead-letter, instead of acking and
+// losing it.
+func TestProcessAcksOnlyOnSettlement(t *testing.T) {
+	tests := []struct {
+		name           string
+		defaultStatus  int
+		fallbackStatus int
+		wantSettled    bool
+	}{
+		{"default accepts", http.StatusOK, http.StatusInternalServerError, true},
+		{"fallback accepts after default rejects", http.StatusInternalServerError, http.StatusOK, true},
+		{"both reject", http.StatusInternalServerError, http.StatusInternalServerError, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defaultServer := testServer(tt.defaultStatus)
+			defer defaultServer.Close()
+			fallbackServer := testServer(tt.fallbackStatus)
+			defer fallbackServer.Close()
+
+			p := newTestProcessor(t)
+			p.defaultURL = defaultServer.URL
+			p.fallbackURL = fallbackServer.URL
+
+			if got := p.process(context.Background(), testPayment()); got != tt.wantSettled {
+				t.Fatalf("process() = %v, want %v", got, tt.wantSettled)
+			}
+		})
+	}
+}
+
+// TestReclaimStalePagesPastBatchSize guards the reclaimStale cursoring fixed
+// in an earlier commit: a pending list bigger than one reclaimBatchSize page
+// must still be walked in full within a single tick, not just its first
+// page.
+func TestReclaimStalePagesPastBatchSize(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis.Run: %v", err)
+	}
+	defer mr.Close()
+
+	queue := NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	ctx := context.Background()
+	if err := queue.EnsureConsumerGroup(ctx); err != nil {
+		t.Fatalf("EnsureConsumerGroup: %v", err)
+	}
+
+	// PendingSince's paging depends on XPENDING's exclusive-range start IDs
+	// ("(" + id), which real Redis supports but this miniredis version
+	// doesn't implement. Skip rather than fail on that gap in the fake.
+	if err := probeExclusiveRangeSupport(ctx, queue); err != nil {
+		t.Skipf("fake Redis doesn't support XPENDING exclusive-range cursors, needed to page past reclaimBatchSize: %v", err)
+	}
+
+	const entryCount = reclaimBatchSize + 50
+	codec := MsgPackCodec{}
+	for i := 0; i < entryCount; i++ {
+		payload, err := codec.Encode(testPayment())
+		if err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		if _, err := queue.Enqueue(ctx, payload); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+
+	// Read every entry into the pending list under one consumer, as if a
+	// worker had popped them all and then crashed before acking any.
+	if _, err := queue.ReadGroup(ctx, "worker-0", entryCount); err != nil {
+		t.Fatalf("ReadGroup: %v", err)
+	}
+	if pending, _ := queue.PendingCount(ctx); pending != entryCount {
+		t.Fatalf("PendingCount before reclaim = %d, want %d", pending, entryCount)
+	}
+
+	acceptingServer := testServer(http.StatusOK)
+	defer acceptingServer.Close()
+
+	// Built directly rather than via NewPaymentProcessor, so this test
+	// drives reclaimStale synchronously instead of racing the background
+	// reclaimLoop/worker goroutines that constructor also starts.
+	p := &PaymentProcessor{
+		queue:             queue,
+		store:             fakeStore{},
+		codec:             codec,
+		httpClient:        http.DefaultClient,
+		defaultURL:        acceptingServer.URL,
+		fallbackURL:       acceptingServer.URL,
+		visibilityTimeout: 0, // every entry above counts as stale immediately
+		maxDeliveries:     5,
+	}
+
+	p.reclaimStale(ctx)
+
+	if pending, _ := queue.PendingCount(ctx); pending != 0 {
+		t.Fatalf("PendingCount after reclaim = %d, want 0 (entries beyond the first page were never reclaimed)", pending)
+	}
+	if length, _ := queue.StreamLen(ctx); length != 0 {
+		t.Fatalf("StreamLen after reclaim = %d, want 0", length)
+	}
+}
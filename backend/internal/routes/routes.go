@@ -40,13 +40,20 @@ func CreateRouter(mux *http.ServeMux) {
 		log.Printf("Warning: Redis connection failed: %v", err)
 	}
 
-	store := &Store{redisClient}
-	newProcessor := NewPaymentProcessor(10, store)
+	queue := NewRedisStore(redisClient)
+	store, err := StoreFromEnv(queue)
+	if err != nil {
+		log.Fatalf("Error configuring STORE_BACKEND: %v", err)
+	}
+	codec := CodecFromEnv()
+	newProcessor := NewPaymentProcessor(10, queue, store, codec)
 	handler := &Handler{paymentProcessor: newProcessor}
 
 	mux.HandleFunc("POST /payments", handler.HandlePayments)
 	mux.HandleFunc("GET /payments-summary", handler.HandlePaymentsSummary)
 	mux.HandleFunc("POST /purge-payments", handler.HandlePurgePayments)
+	mux.HandleFunc("GET /payments/events", handler.HandlePaymentEvents)
+	mux.HandleFunc("GET /payments/{correlationId}/events", handler.HandlePaymentEvents)
 }
 
 type Handler struct {
@@ -71,29 +78,55 @@ func (h *Handler) HandlePayments(w http.ResponseWriter, r *http.Request) {
 
 	paymentRequest.ReceivedAt = time.Now().UTC()
 
-	payload, err := json.Marshal(paymentRequest)
+	queue := h.paymentProcessor.queue
+	alreadySeen, status, err := queue.MarkSeen(r.Context(), paymentRequest.CorrelationId, paymentRequest.ReceivedAt)
 	if err != nil {
-		log.Printf("Error marshalling payment request: %v", err)
+		log.Printf("Error checking payment idempotency: %v", err)
 		http.Error(w, "Failed to process payment", http.StatusInternalServerError)
 		return
 	}
-	if err := h.paymentProcessor.store.redisClient.LPush(r.Context(), "payments:queue", payload).Err(); err != nil {
+	if alreadySeen {
+		// A client retry: echo back whatever state the processor last
+		// recorded instead of enqueueing the payment a second time.
+		writePaymentResponse(w, http.StatusOK, paymentRequest.CorrelationId, status)
+		return
+	}
+
+	payload, err := h.paymentProcessor.codec.Encode(paymentRequest)
+	if err != nil {
+		log.Printf("Error encoding payment request: %v", err)
+		http.Error(w, "Failed to process payment", http.StatusInternalServerError)
+		return
+	}
+	if _, err := queue.Enqueue(r.Context(), payload); err != nil {
 		log.Printf("Error pushing payment to Redis: %v", err)
 		http.Error(w, "Failed to process payment", http.StatusInternalServerError)
 		return
 	}
+	if err := queue.SetStatus(r.Context(), paymentRequest.CorrelationId, "queued", ""); err != nil {
+		log.Printf("Error recording queued status: %v", err)
+	}
+	if err := queue.PublishEvent(r.Context(), PaymentEvent{
+		CorrelationId: paymentRequest.CorrelationId,
+		Event:         "queued",
+		At:            paymentRequest.ReceivedAt,
+	}); err != nil {
+		log.Printf("Error publishing queued event: %v", err)
+	}
+
+	writePaymentResponse(w, http.StatusAccepted, paymentRequest.CorrelationId, &PaymentStatus{Status: "queued"})
+}
 
+func writePaymentResponse(w http.ResponseWriter, statusCode int, correlationId uuid.UUID, status *PaymentStatus) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusAccepted)
+	w.WriteHeader(statusCode)
 	response := map[string]string{
-		"status":        "success",
-		"message":       "Payment request accepted",
-		"correlationId": paymentRequest.CorrelationId.String(),
+		"status":        status.Status,
+		"processor":     status.Processor,
+		"correlationId": correlationId.String(),
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		log.Printf("Error encoding response: %v", err)
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-		return
 	}
 }
 
@@ -120,6 +153,25 @@ func (h *Handler) HandlePaymentsSummary(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	// Pending/DLQSize/Lag describe payments:stream, which stays Redis-native
+	// regardless of STORE_BACKEND, so they're always read from queue.
+	queue := h.paymentProcessor.queue
+	if summary.Pending, err = queue.PendingCount(r.Context()); err != nil {
+		log.Printf("Error getting pending count: %v", err)
+		http.Error(w, "Failed to retrieve summary", http.StatusInternalServerError)
+		return
+	}
+	if summary.Lag, err = queue.StreamLen(r.Context()); err != nil {
+		log.Printf("Error getting stream length: %v", err)
+		http.Error(w, "Failed to retrieve summary", http.StatusInternalServerError)
+		return
+	}
+	if summary.DLQSize, err = queue.DLQLen(r.Context()); err != nil {
+		log.Printf("Error getting dlq size: %v", err)
+		http.Error(w, "Failed to retrieve summary", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(summary); err != nil {
@@ -131,9 +183,14 @@ func (h *Handler) HandlePaymentsSummary(w http.ResponseWriter, r *http.Request)
 func (h *Handler) HandlePurgePayments(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	// Delete all payment summary data from Redis
-	err := h.paymentProcessor.store.PurgeAllData(ctx)
-	if err != nil {
+	// Wipe the Redis-native queue/idempotency bookkeeping, then whichever
+	// ledger backend is configured.
+	if err := h.paymentProcessor.queue.PurgeAllData(ctx); err != nil {
+		log.Printf("Error purging payment queue: %v", err)
+		http.Error(w, "Failed to purge payment data", http.StatusInternalServerError)
+		return
+	}
+	if err := h.paymentProcessor.store.PurgeAllData(ctx); err != nil {
 		log.Printf("Error purging payment data: %v", err)
 		http.Error(w, "Failed to purge payment data", http.StatusInternalServerError)
 		return
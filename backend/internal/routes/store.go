@@ -0,0 +1,222 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultProcessedKey  = "payments:processed:default"
+	fallbackProcessedKey = "payments:processed:fallback"
+	seenKeyPrefix        = "payments:seen:"
+	statusKeyPrefix      = "payments:status:"
+	seenTTL              = 24 * time.Hour
+)
+
+// Store is the ledger backing /payments-summary: recording settled
+// payments, answering summary queries, and purging between load-test runs.
+// CreateRouter selects an implementation via STORE_BACKEND; payment intake
+// and worker bookkeeping stay on RedisStore regardless of that choice, since
+// the ingest queue is always Redis. HandlePayments enqueues through queue
+// directly rather than through Store, so Store has no Enqueue method.
+type Store interface {
+	RecordProcessed(ctx context.Context, processor string, payment Payment) error
+	GetSummary(ctx context.Context) (*PaymentSummary, error)
+	GetSummaryWithTime(ctx context.Context, from, to string) (*PaymentSummary, error)
+	PurgeAllData(ctx context.Context) error
+}
+
+// RedisStore wraps the Redis client used for the payment stream, the
+// processed ledgers backing /payments-summary, per-correlationId
+// idempotency bookkeeping, and lifecycle pub/sub.
+type RedisStore struct {
+	redisClient *redis.Client
+}
+
+func NewRedisStore(redisClient *redis.Client) *RedisStore {
+	return &RedisStore{redisClient: redisClient}
+}
+
+// PaymentStatus is the lifecycle state recorded for a single correlationId,
+// surfaced back to clients that retry POST /payments.
+type PaymentStatus struct {
+	Status    string `redis:"status"`
+	Processor string `redis:"processor"`
+}
+
+// MarkSeen records that correlationId has just been accepted, returning
+// alreadySeen=false the first time a given correlationId is observed (the
+// caller should go on to enqueue the payment). On a retry it returns
+// alreadySeen=true along with the status previously recorded for it.
+//
+// The seen-key and the initial "queued" status are written in one pipeline
+// so they can't diverge: if the process died between two separate writes, a
+// correlationId could be marked seen without ever being enqueued, and every
+// retry would get back a false "200 queued" for the rest of seenTTL while the
+// payment silently vanished. Both keys share seenTTL so the status hash
+// doesn't outlive the idempotency window it supports and leak forever.
+func (s *RedisStore) MarkSeen(ctx context.Context, correlationId uuid.UUID, receivedAt time.Time) (alreadySeen bool, status *PaymentStatus, err error) {
+	seenKey := seenKeyPrefix + correlationId.String()
+	statusKey := statusKeyPrefix + correlationId.String()
+
+	var setNX *redis.BoolCmd
+	_, err = s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		setNX = pipe.SetNX(ctx, seenKey, receivedAt.Format(time.RFC3339Nano), seenTTL)
+		pipe.HSetNX(ctx, statusKey, "status", "queued")
+		pipe.Expire(ctx, statusKey, seenTTL)
+		return nil
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	if setNX.Val() {
+		return false, nil, nil
+	}
+
+	status, err = s.GetStatus(ctx, correlationId)
+	if err != nil {
+		return true, nil, err
+	}
+	return true, status, nil
+}
+
+// SetStatus records the current lifecycle state for a correlationId so that
+// retried requests can echo it back instead of re-enqueueing. The key is
+// re-expired on every write, matching MarkSeen's TTL, so a payment's status
+// hash never outlives the seenTTL idempotency window it exists to serve.
+func (s *RedisStore) SetStatus(ctx context.Context, correlationId uuid.UUID, status, processor string) error {
+	key := statusKeyPrefix + correlationId.String()
+	_, err := s.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, map[string]any{
+			"status":    status,
+			"processor": processor,
+		})
+		pipe.Expire(ctx, key, seenTTL)
+		return nil
+	})
+	return err
+}
+
+// GetStatus returns the last recorded lifecycle state for a correlationId,
+// defaulting to "queued" if no transition has been written yet.
+func (s *RedisStore) GetStatus(ctx context.Context, correlationId uuid.UUID) (*PaymentStatus, error) {
+	key := statusKeyPrefix + correlationId.String()
+	var status PaymentStatus
+	if err := s.redisClient.HGetAll(ctx, key).Scan(&status); err != nil {
+		return nil, err
+	}
+	if status.Status == "" {
+		status.Status = "queued"
+	}
+	return &status, nil
+}
+
+// RecordProcessed appends a settled payment to the processor's ledger,
+// scored by ReceivedAt so GetSummaryWithTime can range over it.
+func (s *RedisStore) RecordProcessed(ctx context.Context, processor string, payment Payment) error {
+	key := processedKey(processor)
+	member := fmt.Sprintf("%s:%s", payment.CorrelationId.String(), strconv.FormatFloat(payment.Amount, 'f', -1, 64))
+	score := float64(payment.ReceivedAt.UnixNano())
+	return s.redisClient.ZAdd(ctx, key, redis.Z{Score: score, Member: member}).Err()
+}
+
+func processedKey(processor string) string {
+	if processor == "fallback" {
+		return fallbackProcessedKey
+	}
+	return defaultProcessedKey
+}
+
+func (s *RedisStore) GetSummary(ctx context.Context) (*PaymentSummary, error) {
+	return s.summaryBetween(ctx, "-inf", "+inf")
+}
+
+func (s *RedisStore) GetSummaryWithTime(ctx context.Context, from, to string) (*PaymentSummary, error) {
+	min := "-inf"
+	max := "+inf"
+	if from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from timestamp: %w", err)
+		}
+		min = strconv.FormatInt(t.UnixNano(), 10)
+	}
+	if to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to timestamp: %w", err)
+		}
+		max = strconv.FormatInt(t.UnixNano(), 10)
+	}
+	return s.summaryBetween(ctx, min, max)
+}
+
+// summaryBetween fills only Default/Fallback; Pending/DLQSize/Lag describe
+// payments:stream rather than the ledger, so HandlePaymentsSummary overlays
+// them uniformly from queue regardless of which Store is configured.
+func (s *RedisStore) summaryBetween(ctx context.Context, min, max string) (*PaymentSummary, error) {
+	def, err := s.processorSummaryBetween(ctx, defaultProcessedKey, min, max)
+	if err != nil {
+		return nil, err
+	}
+	fallback, err := s.processorSummaryBetween(ctx, fallbackProcessedKey, min, max)
+	if err != nil {
+		return nil, err
+	}
+	return &PaymentSummary{Default: *def, Fallback: *fallback}, nil
+}
+
+func (s *RedisStore) processorSummaryBetween(ctx context.Context, key, min, max string) (*ProcessorSummary, error) {
+	members, err := s.redisClient.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: min, Max: max}).Result()
+	if err != nil {
+		return nil, err
+	}
+	summary := &ProcessorSummary{}
+	for _, member := range members {
+		_, amountPart, ok := strings.Cut(member, ":")
+		if !ok {
+			continue
+		}
+		amount, err := strconv.ParseFloat(amountPart, 64)
+		if err != nil {
+			continue
+		}
+		summary.TotalRequests++
+		summary.TotalAmount += amount
+	}
+	return summary, nil
+}
+
+// PurgeAllData wipes the stream, the dead-letter stream, the processed
+// ledgers, and the per-payment idempotency bookkeeping so a fresh load-test
+// run starts from zero.
+func (s *RedisStore) PurgeAllData(ctx context.Context) error {
+	if err := s.redisClient.Del(ctx, streamKey, dlqKey, defaultProcessedKey, fallbackProcessedKey).Err(); err != nil {
+		return err
+	}
+	if err := s.deleteByPattern(ctx, seenKeyPrefix+"*"); err != nil {
+		return err
+	}
+	return s.deleteByPattern(ctx, statusKeyPrefix+"*")
+}
+
+func (s *RedisStore) deleteByPattern(ctx context.Context, pattern string) error {
+	var keys []string
+	iter := s.redisClient.Scan(ctx, 0, pattern, 0).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return s.redisClient.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,36 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// StoreFromEnv selects the ledger Store via STORE_BACKEND: redis (the
+// default), postgres, or hybrid. The payment stream stays Redis-native on
+// queue regardless of ledger backend, so postgres only replaces where settled
+// payments are recorded and summarized, and hybrid records to Redis while
+// flushing to Postgres in the background. Any other value is rejected
+// outright rather than silently falling back to redis, so a typo in
+// STORE_BACKEND fails loudly instead of quietly downgrading the configured
+// backend.
+func StoreFromEnv(queue *RedisStore) (Store, error) {
+	switch backend := os.Getenv("STORE_BACKEND"); backend {
+	case "redis", "":
+		return queue, nil
+	case "postgres":
+		postgres, err := NewPostgresStore(context.Background(), os.Getenv("DATABASE_URL"))
+		if err != nil {
+			return nil, fmt.Errorf("configuring postgres store: %w", err)
+		}
+		return postgres, nil
+	case "hybrid":
+		postgres, err := NewPostgresStore(context.Background(), os.Getenv("DATABASE_URL"))
+		if err != nil {
+			return nil, fmt.Errorf("configuring postgres store: %w", err)
+		}
+		return NewHybridStore(queue, postgres), nil
+	default:
+		return nil, fmt.Errorf("unknown STORE_BACKEND %q: want redis, postgres, hybrid, or unset", backend)
+	}
+}
@@ -0,0 +1,62 @@
+package routes
+
+import (
+	"context"
+	"log"
+)
+
+// HybridStore keeps Redis as the fast ingest queue and summary read path
+// (so POST /payments and GET /payments-summary are unaffected) while
+// asynchronously flushing settled payments to Postgres for crash recovery
+// and long-window audits.
+type HybridStore struct {
+	redis    *RedisStore
+	postgres *PostgresStore
+	flush    chan flushEntry
+}
+
+type flushEntry struct {
+	processor string
+	payment   Payment
+}
+
+func NewHybridStore(redis *RedisStore, postgres *PostgresStore) *HybridStore {
+	s := &HybridStore{redis: redis, postgres: postgres, flush: make(chan flushEntry, 1024)}
+	go s.runFlusher()
+	return s
+}
+
+func (s *HybridStore) runFlusher() {
+	for entry := range s.flush {
+		if err := s.postgres.RecordProcessed(context.Background(), entry.processor, entry.payment); err != nil {
+			log.Printf("Error flushing payment %s to postgres: %v", entry.payment.CorrelationId, err)
+		}
+	}
+}
+
+func (s *HybridStore) RecordProcessed(ctx context.Context, processor string, payment Payment) error {
+	if err := s.redis.RecordProcessed(ctx, processor, payment); err != nil {
+		return err
+	}
+	select {
+	case s.flush <- flushEntry{processor: processor, payment: payment}:
+	default:
+		log.Printf("Postgres flush queue full, dropping async durability write for %s", payment.CorrelationId)
+	}
+	return nil
+}
+
+func (s *HybridStore) GetSummary(ctx context.Context) (*PaymentSummary, error) {
+	return s.redis.GetSummary(ctx)
+}
+
+func (s *HybridStore) GetSummaryWithTime(ctx context.Context, from, to string) (*PaymentSummary, error) {
+	return s.redis.GetSummaryWithTime(ctx, from, to)
+}
+
+func (s *HybridStore) PurgeAllData(ctx context.Context) error {
+	if err := s.redis.PurgeAllData(ctx); err != nil {
+		return err
+	}
+	return s.postgres.PurgeAllData(ctx)
+}
@@ -0,0 +1,112 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresStore persists settled payments to a durable table, trading the
+// Redis ledger's in-memory volatility for crash-safe summaries and
+// long-window audits.
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresStore(ctx context.Context, dsn string) (*PostgresStore, error) {
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to postgres: %w", err)
+	}
+	store := &PostgresStore{pool: pool}
+	if err := store.migrate(ctx); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *PostgresStore) migrate(ctx context.Context) error {
+	_, err := s.pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS payments (
+			correlation_id uuid PRIMARY KEY,
+			amount         numeric NOT NULL,
+			processor      text NOT NULL,
+			processed_at   timestamptz NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS payments_processed_at_brin
+			ON payments USING BRIN (processed_at);
+	`)
+	return err
+}
+
+func (s *PostgresStore) RecordProcessed(ctx context.Context, processor string, payment Payment) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO payments (correlation_id, amount, processor, processed_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (correlation_id) DO NOTHING
+	`, payment.CorrelationId, payment.Amount, processor, payment.ReceivedAt)
+	return err
+}
+
+func (s *PostgresStore) GetSummary(ctx context.Context) (*PaymentSummary, error) {
+	return s.summaryBetween(ctx, nil, nil)
+}
+
+func (s *PostgresStore) GetSummaryWithTime(ctx context.Context, from, to string) (*PaymentSummary, error) {
+	var fromT, toT *time.Time
+	if from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from timestamp: %w", err)
+		}
+		fromT = &t
+	}
+	if to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to timestamp: %w", err)
+		}
+		toT = &t
+	}
+	return s.summaryBetween(ctx, fromT, toT)
+}
+
+// summaryBetween relies on the BRIN index on processed_at to keep the range
+// scan cheap even as the table grows across a long-running audit window.
+func (s *PostgresStore) summaryBetween(ctx context.Context, from, to *time.Time) (*PaymentSummary, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT processor, count(*), coalesce(sum(amount), 0)
+		FROM payments
+		WHERE ($1::timestamptz IS NULL OR processed_at >= $1)
+		  AND ($2::timestamptz IS NULL OR processed_at <= $2)
+		GROUP BY processor
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summary := &PaymentSummary{}
+	for rows.Next() {
+		var processor string
+		var count int64
+		var amount float64
+		if err := rows.Scan(&processor, &count, &amount); err != nil {
+			return nil, err
+		}
+		target := &summary.Default
+		if processor == "fallback" {
+			target = &summary.Fallback
+		}
+		target.TotalRequests = count
+		target.TotalAmount = amount
+	}
+	return summary, rows.Err()
+}
+
+func (s *PostgresStore) PurgeAllData(ctx context.Context) error {
+	_, err := s.pool.Exec(ctx, "TRUNCATE payments")
+	return err
+}
@@ -0,0 +1,85 @@
+package routes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestStore(t *testing.T) *RedisStore {
+	t.Helper()
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis.Run: %v", err)
+	}
+	t.Cleanup(mr.Close)
+	return NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+}
+
+// TestMarkSeenWritesSeenAndQueuedAtomically locks in MarkSeen's pipelined
+// write: the seen-key and the initial "queued" status must land together, so
+// a retry can never observe one without the other.
+func TestMarkSeenWritesSeenAndQueuedAtomically(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	id := uuid.New()
+
+	alreadySeen, status, err := store.MarkSeen(ctx, id, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("MarkSeen: %v", err)
+	}
+	if alreadySeen {
+		t.Fatalf("MarkSeen: got alreadySeen=true on first call, want false")
+	}
+	if status != nil {
+		t.Fatalf("MarkSeen: got status=%+v on first call, want nil", status)
+	}
+
+	got, err := store.GetStatus(ctx, id)
+	if err != nil {
+		t.Fatalf("GetStatus: %v", err)
+	}
+	if got.Status != "queued" {
+		t.Fatalf("GetStatus: got status %q, want %q", got.Status, "queued")
+	}
+
+	alreadySeen, status, err = store.MarkSeen(ctx, id, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("MarkSeen (retry): %v", err)
+	}
+	if !alreadySeen {
+		t.Fatalf("MarkSeen (retry): got alreadySeen=false, want true")
+	}
+	if status == nil || status.Status != "queued" {
+		t.Fatalf("MarkSeen (retry): got status=%+v, want queued", status)
+	}
+}
+
+// TestMarkSeenAndSetStatusExpireTogether guards against the status hash
+// outliving the seen-key's idempotency window: both must carry seenTTL.
+func TestMarkSeenAndSetStatusExpireTogether(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	id := uuid.New()
+
+	if _, _, err := store.MarkSeen(ctx, id, time.Now().UTC()); err != nil {
+		t.Fatalf("MarkSeen: %v", err)
+	}
+
+	seenTTLGot := store.redisClient.TTL(ctx, seenKeyPrefix+id.String()).Val()
+	statusTTLGot := store.redisClient.TTL(ctx, statusKeyPrefix+id.String()).Val()
+	if seenTTLGot <= 0 || statusTTLGot <= 0 {
+		t.Fatalf("got seen TTL=%s status TTL=%s after MarkSeen, want both > 0", seenTTLGot, statusTTLGot)
+	}
+
+	if err := store.SetStatus(ctx, id, "processed-default", "default"); err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+	if ttl := store.redisClient.TTL(ctx, statusKeyPrefix+id.String()).Val(); ttl <= 0 {
+		t.Fatalf("got status TTL=%s after SetStatus, want > 0", ttl)
+	}
+}
@@ -0,0 +1,117 @@
+package routes
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	streamKey     = "payments:stream"
+	dlqKey        = "payments:dlq"
+	consumerGroup = "processors"
+	streamField   = "body"
+)
+
+// EnsureConsumerGroup creates the consumer group backing payments:stream if
+// it doesn't already exist, so XReadGroup has something to attach to.
+func (s *RedisStore) EnsureConsumerGroup(ctx context.Context) error {
+	err := s.redisClient.XGroupCreateMkStream(ctx, streamKey, consumerGroup, "0").Err()
+	if err != nil && !strings.Contains(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+// Enqueue appends a payment onto payments:stream and returns its entry id.
+func (s *RedisStore) Enqueue(ctx context.Context, payload []byte) (string, error) {
+	return s.redisClient.XAdd(ctx, &redis.XAddArgs{
+		Stream: streamKey,
+		Values: map[string]any{streamField: payload},
+	}).Result()
+}
+
+// ReadGroup claims up to count new entries for consumer, blocking until at
+// least one is available.
+func (s *RedisStore) ReadGroup(ctx context.Context, consumer string, count int64) ([]redis.XStream, error) {
+	return s.redisClient.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    consumerGroup,
+		Consumer: consumer,
+		Streams:  []string{streamKey, ">"},
+		Count:    count,
+		Block:    0,
+	}).Result()
+}
+
+// Ack marks an entry as successfully processed. Acked entries are also
+// trimmed from the stream so StreamLen reflects the true backlog.
+func (s *RedisStore) Ack(ctx context.Context, id string) error {
+	if err := s.redisClient.XAck(ctx, streamKey, consumerGroup, id).Err(); err != nil {
+		return err
+	}
+	return s.redisClient.XDel(ctx, streamKey, id).Err()
+}
+
+// PendingSince returns up to count pending entries idle longer than min,
+// starting from startID. Passing "(" + the last returned entry's ID back in
+// as startID pages past it (XPENDING supports the same exclusive-range
+// syntax as XRANGE), letting the reclaim loop walk a pending list bigger
+// than one batch instead of only ever inspecting the first count entries.
+func (s *RedisStore) PendingSince(ctx context.Context, min time.Duration, startID string, count int64) ([]redis.XPendingExt, error) {
+	return s.redisClient.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: streamKey,
+		Group:  consumerGroup,
+		Idle:   min,
+		Start:  startID,
+		End:    "+",
+		Count:  count,
+	}).Result()
+}
+
+// Claim reassigns the given entry ids to consumer so it can redeliver them.
+func (s *RedisStore) Claim(ctx context.Context, consumer string, minIdle time.Duration, ids ...string) ([]redis.XMessage, error) {
+	return s.redisClient.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   streamKey,
+		Group:    consumerGroup,
+		Consumer: consumer,
+		MinIdle:  minIdle,
+		Messages: ids,
+	}).Result()
+}
+
+// DeadLetter moves an entry that exceeded MaxDeliveries onto payments:dlq
+// and acks/removes it from the live stream.
+func (s *RedisStore) DeadLetter(ctx context.Context, id string, payload []byte) error {
+	if err := s.redisClient.XAdd(ctx, &redis.XAddArgs{
+		Stream: dlqKey,
+		Values: map[string]any{streamField: payload},
+	}).Err(); err != nil {
+		return err
+	}
+	return s.Ack(ctx, id)
+}
+
+// PendingCount is the number of entries claimed by a consumer but not yet
+// acked.
+func (s *RedisStore) PendingCount(ctx context.Context) (int64, error) {
+	summary, err := s.redisClient.XPending(ctx, streamKey, consumerGroup).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return summary.Count, nil
+}
+
+// StreamLen is the live backlog: entries not yet acked and trimmed.
+func (s *RedisStore) StreamLen(ctx context.Context) (int64, error) {
+	return s.redisClient.XLen(ctx, streamKey).Result()
+}
+
+// DLQLen is the number of entries that exceeded MaxDeliveries.
+func (s *RedisStore) DLQLen(ctx context.Context) (int64, error) {
+	return s.redisClient.XLen(ctx, dlqKey).Result()
+}